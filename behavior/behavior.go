@@ -0,0 +1,11 @@
+// Package behavior provides the building block that the name, flag and cast
+// sub-packages use to turn a plain func(*structmap.FieldPart) error into a
+// structmap.MutationFunc.
+package behavior
+
+import "github.com/amulets/structmap"
+
+// New wraps fn as a structmap.MutationFunc
+func New(fn func(field *structmap.FieldPart) error) structmap.MutationFunc {
+	return structmap.MutationFunc(fn)
+}
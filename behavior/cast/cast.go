@@ -0,0 +1,354 @@
+// Package cast provides a behavior that converts an incoming value into the
+// type a struct field actually expects, so that neither Decoder.Decode nor
+// Decoder.Encode have to special-case every possible source/target pairing.
+package cast
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/amulets/structmap"
+	"github.com/amulets/structmap/behavior"
+	"github.com/amulets/structmap/internal"
+)
+
+// ErrNoConvertible is returned by a TypeCaster func to signal that it
+// doesn't know how to convert the given value, letting ToType fall back to
+// its generic conversion rules
+var ErrNoConvertible = errors.New("cast: value is not convertible")
+
+type (
+	// TypeCasterFunc converts value (whose real type is source) into result
+	TypeCasterFunc func(source reflect.Type, value reflect.Value) (result interface{}, err error)
+
+	// TypeCaster binds a TypeCasterFunc to the concrete type it knows how to produce
+	TypeCaster struct {
+		typ reflect.Type
+		fn  TypeCasterFunc
+	}
+
+	config struct {
+		types []TypeCaster
+	}
+
+	// Option configures ToType
+	Option func(*config)
+)
+
+// Type registers a TypeCasterFunc for the type of exemplar (e.g. big.Int{}, time.Time{})
+func Type(exemplar interface{}, fn TypeCasterFunc) TypeCaster {
+	return TypeCaster{
+		typ: reflect.TypeOf(exemplar),
+		fn:  fn,
+	}
+}
+
+// WithTypes registers custom TypeCasters with ToType, tried before the
+// generic conversion rules
+func WithTypes(types ...TypeCaster) Option {
+	return func(cfg *config) {
+		cfg.types = append(cfg.types, types...)
+	}
+}
+
+// ToKind returns the reflect.Kind of typ, resolving through pointers
+func ToKind(typ reflect.Type) reflect.Kind {
+	return internal.Type(typ).Kind()
+}
+
+// ToType converts a field's incoming value to the type the field expects,
+// trying any registered TypeCasters first and falling back to reflection
+// based conversion of scalars, slices, arrays and maps
+func ToType(opts ...Option) structmap.MutationFunc {
+	cfg := &config{}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return behavior.New(func(field *structmap.FieldPart) error {
+		if field.Value == nil {
+			return nil
+		}
+
+		result, err := convert(cfg, internal.Type(field.Type), reflect.ValueOf(field.Value))
+		if err != nil {
+			return err
+		}
+
+		field.Value = result
+
+		return nil
+	})
+}
+
+func convert(cfg *config, target reflect.Type, value reflect.Value) (interface{}, error) {
+	value = internal.Value(value, true)
+
+	if !value.IsValid() {
+		return nil, nil
+	}
+
+	for _, caster := range cfg.types {
+		if caster.typ != target {
+			continue
+		}
+
+		result, err := caster.fn(value.Type(), value)
+		if err == nil {
+			return result, nil
+		}
+
+		if !errors.Is(err, ErrNoConvertible) {
+			return nil, err
+		}
+	}
+
+	switch target.Kind() {
+	case reflect.Slice, reflect.Array:
+		return convertSequence(cfg, target, value)
+	case reflect.Map:
+		return convertMap(cfg, target, value)
+	default:
+		return convertScalar(target, value)
+	}
+}
+
+func convertSequence(cfg *config, target reflect.Type, value reflect.Value) (interface{}, error) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return convertScalar(target, value)
+	}
+
+	elemType := target.Elem()
+
+	var result reflect.Value
+	if target.Kind() == reflect.Array {
+		result = reflect.New(target).Elem()
+	} else {
+		result = reflect.MakeSlice(target, value.Len(), value.Len())
+	}
+
+	for i := 0; i < value.Len() && i < result.Len(); i++ {
+		elem, err := convert(cfg, internal.Type(elemType), value.Index(i))
+		if err != nil {
+			return nil, err
+		}
+
+		// A nil elem (e.g. from a nil pointer source) leaves the slot at
+		// its zero value; reflect.ValueOf(nil) is an invalid Value and
+		// would panic SetValue
+		if elem == nil {
+			continue
+		}
+
+		internal.SetValue(result.Index(i), reflect.ValueOf(elem))
+	}
+
+	return result.Interface(), nil
+}
+
+func convertMap(cfg *config, target reflect.Type, value reflect.Value) (interface{}, error) {
+	if value.Kind() != reflect.Map {
+		return convertScalar(target, value)
+	}
+
+	keyType := target.Key()
+	elemType := target.Elem()
+
+	result := reflect.MakeMapWithSize(target, value.Len())
+
+	for _, key := range value.MapKeys() {
+		k, err := convert(cfg, internal.Type(keyType), key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := convert(cfg, internal.Type(elemType), value.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
+
+		if k == nil {
+			continue
+		}
+
+		keyValue := reflect.New(keyType).Elem()
+		internal.SetValue(keyValue, reflect.ValueOf(k))
+
+		elemValue := reflect.New(elemType).Elem()
+
+		// A nil v (e.g. from a nil pointer source) leaves the element at
+		// its zero value; reflect.ValueOf(nil) is an invalid Value and
+		// would panic SetValue
+		if v != nil {
+			internal.SetValue(elemValue, reflect.ValueOf(v))
+		}
+
+		result.SetMapIndex(keyValue, elemValue)
+	}
+
+	return result.Interface(), nil
+}
+
+// convertScalar converts value to target, weakly coercing between string,
+// bool and the numeric kinds (e.g. the string "1832" to an int, or the int
+// 1 to a bool) since reflect.Value.ConvertibleTo refuses those conversions
+// even though a Decode source (JSON-like map[string]interface{} values) and
+// a struct field routinely disagree on which of the three it uses
+func convertScalar(target reflect.Type, value reflect.Value) (interface{}, error) {
+	if value.Type() == target {
+		return value.Interface(), nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		if s, ok := weakString(value); ok {
+			return reflect.ValueOf(s).Convert(target).Interface(), nil
+		}
+	case reflect.Bool:
+		if b, ok := weakBool(value); ok {
+			return b, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := weakInt(value); ok {
+			return reflect.ValueOf(i).Convert(target).Interface(), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, ok := weakUint(value); ok {
+			return reflect.ValueOf(u).Convert(target).Interface(), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := weakFloat(value); ok {
+			return reflect.ValueOf(f).Convert(target).Interface(), nil
+		}
+	}
+
+	if value.Type().ConvertibleTo(target) {
+		return value.Convert(target).Interface(), nil
+	}
+
+	return value.Interface(), nil
+}
+
+// weakString renders a bool/numeric value as its decimal/textual form,
+// rather than the unicode-codepoint conversion reflect.Convert would
+// produce for e.g. an int
+func weakString(value reflect.Value) (string, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64), true
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), true
+	}
+
+	return "", false
+}
+
+// weakBool coerces a numeric value (non-zero is true) or a parseable string
+// into a bool
+func weakBool(value reflect.Value) (bool, bool) {
+	switch value.Kind() {
+	case reflect.Bool:
+		return value.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() != 0, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() != 0, true
+	case reflect.Float32, reflect.Float64:
+		return value.Float() != 0, true
+	case reflect.String:
+		b, err := strconv.ParseBool(value.String())
+		if err != nil {
+			return false, false
+		}
+
+		return b, true
+	}
+
+	return false, false
+}
+
+// weakInt coerces a bool, another numeric kind, or a parseable string into
+// an int64
+func weakInt(value reflect.Value) (int64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return int64(value.Float()), true
+	case reflect.Bool:
+		return boolToInt64(value.Bool()), true
+	case reflect.String:
+		i, err := strconv.ParseInt(value.String(), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return i, true
+	}
+
+	return 0, false
+}
+
+// weakUint coerces a bool, another numeric kind, or a parseable string into
+// a uint64
+func weakUint(value reflect.Value) (uint64, bool) {
+	switch value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(value.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return uint64(value.Float()), true
+	case reflect.Bool:
+		return uint64(boolToInt64(value.Bool())), true
+	case reflect.String:
+		u, err := strconv.ParseUint(value.String(), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return u, true
+	}
+
+	return 0, false
+}
+
+// weakFloat coerces a bool, another numeric kind, or a parseable string
+// into a float64
+func weakFloat(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Bool:
+		return float64(boolToInt64(value.Bool())), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(value.String(), 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return f, true
+	}
+
+	return 0, false
+}
+
+func boolToInt64(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,104 @@
+package cast
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+
+	"github.com/amulets/structmap"
+	"github.com/amulets/structmap/behavior"
+)
+
+// Interfaces routes a field's incoming value through structmap.Unmarshaler,
+// json.Unmarshaler, encoding.TextUnmarshaler or encoding.BinaryUnmarshaler
+// when the destination field implements one of them, bypassing the usual
+// struct recursion / reflect conversion rules. It is meant to run alongside
+// ToType() (e.g. before it) so types such as time.Time, big.Int, net.IP or
+// uuid.UUID no longer need a hand-registered TypeCaster.
+func Interfaces() structmap.MutationFunc {
+	return behavior.New(func(field *structmap.FieldPart) error {
+		if field.Direction != structmap.DirectionDecode || field.Value == nil {
+			return nil
+		}
+
+		if !field.Target.IsValid() {
+			return nil
+		}
+
+		ptr := addressable(field.Target)
+		if !ptr.IsValid() || !ptr.CanInterface() {
+			return nil
+		}
+
+		handled, err := unmarshal(ptr.Interface(), field.Value)
+		if err != nil {
+			return err
+		}
+
+		if handled {
+			field.Skip = true
+		}
+
+		return nil
+	})
+}
+
+// addressable returns a pointer to target's underlying value, allocating
+// through a nil pointer field when needed
+func addressable(target reflect.Value) reflect.Value {
+	if target.Kind() == reflect.Ptr {
+		if target.IsZero() {
+			if !target.CanSet() {
+				return reflect.Value{}
+			}
+
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+
+		return target
+	}
+
+	if target.CanAddr() {
+		return target.Addr()
+	}
+
+	return reflect.Value{}
+}
+
+func unmarshal(dest interface{}, value interface{}) (bool, error) {
+	if u, ok := dest.(structmap.Unmarshaler); ok {
+		return true, u.UnmarshalStructmap(value)
+	}
+
+	if u, ok := dest.(encoding.TextUnmarshaler); ok {
+		switch v := value.(type) {
+		case string:
+			return true, u.UnmarshalText([]byte(v))
+		case []byte:
+			return true, u.UnmarshalText(v)
+		}
+	}
+
+	// Tried after TextUnmarshaler: a bare string/[]byte source is usually a
+	// raw token (e.g. "123" for a big.Int), not a quoted JSON value, and
+	// many UnmarshalJSON implementations just forward to UnmarshalText
+	// expecting exactly that raw form.
+	if u, ok := dest.(json.Unmarshaler); ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return false, nil
+		}
+
+		return true, u.UnmarshalJSON(data)
+	}
+
+	if u, ok := dest.(encoding.BinaryUnmarshaler); ok {
+		if v, ok := value.([]byte); ok {
+			return true, u.UnmarshalBinary(v)
+		}
+
+		return false, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,172 @@
+package cast_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/amulets/structmap"
+	"github.com/amulets/structmap/behavior/cast"
+	"github.com/amulets/structmap/behavior/name"
+)
+
+// big.Int's UnmarshalJSON forwards to UnmarshalText expecting the bare
+// token, not a quoted JSON string, so Interfaces must try
+// encoding.TextUnmarshaler before json.Unmarshaler for a raw string source.
+func TestInterfacesTextUnmarshalerBeforeJSON(t *testing.T) {
+	s := &struct {
+		Number big.Int
+	}{}
+
+	m := map[string]interface{}{
+		"Number": "123456789012345678901234567890",
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, cast.Interfaces()))
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Fatal(err)
+	}
+
+	want, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if !ok {
+		t.Fatalf("test itself is broken: could not parse the expected value")
+	}
+
+	if s.Number.Cmp(want) != 0 {
+		t.Errorf("Number = %s, want %s", s.Number.String(), want.String())
+	}
+}
+
+// TestInterfacesNilPointerField decodes into a *big.Int field that starts
+// out nil, exercising addressable()'s allocate-through-a-nil-pointer path.
+func TestInterfacesNilPointerField(t *testing.T) {
+	s := &struct {
+		Number *big.Int
+	}{}
+
+	m := map[string]interface{}{
+		"Number": "123456789012345678901234567890",
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, cast.Interfaces()))
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Fatal(err)
+	}
+
+	want, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if !ok {
+		t.Fatalf("test itself is broken: could not parse the expected value")
+	}
+
+	if s.Number == nil {
+		t.Fatal("Number was not allocated")
+	}
+
+	if s.Number.Cmp(want) != 0 {
+		t.Errorf("Number = %s, want %s", s.Number.String(), want.String())
+	}
+}
+
+type structmapUnmarshaler struct {
+	Value string
+}
+
+func (u *structmapUnmarshaler) UnmarshalStructmap(value interface{}) error {
+	u.Value = fmt.Sprintf("%v", value)
+
+	return nil
+}
+
+// TestInterfacesStructmapUnmarshaler checks that a type implementing the
+// package-local structmap.Unmarshaler is routed through it.
+func TestInterfacesStructmapUnmarshaler(t *testing.T) {
+	s := &struct {
+		Value structmapUnmarshaler
+	}{}
+
+	m := map[string]interface{}{
+		"Value": 42,
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, cast.Interfaces()))
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Value.Value != "42" {
+		t.Errorf("Value = %q, want %q", s.Value.Value, "42")
+	}
+}
+
+type jsonOnlyUnmarshaler struct {
+	Value string
+}
+
+func (u *jsonOnlyUnmarshaler) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	u.Value = v
+
+	return nil
+}
+
+// TestInterfacesJSONUnmarshaler checks a type implementing only
+// json.Unmarshaler (no TextUnmarshaler) is still routed through it.
+func TestInterfacesJSONUnmarshaler(t *testing.T) {
+	s := &struct {
+		Value jsonOnlyUnmarshaler
+	}{}
+
+	m := map[string]interface{}{
+		"Value": "hello",
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, cast.Interfaces()))
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Value.Value != "hello" {
+		t.Errorf("Value = %q, want %q", s.Value.Value, "hello")
+	}
+}
+
+type binaryOnlyUnmarshaler struct {
+	Value []byte
+}
+
+func (u *binaryOnlyUnmarshaler) UnmarshalBinary(data []byte) error {
+	u.Value = append([]byte(nil), data...)
+
+	return nil
+}
+
+// TestInterfacesBinaryUnmarshaler checks that a []byte source is routed
+// through encoding.BinaryUnmarshaler when nothing else applies.
+func TestInterfacesBinaryUnmarshaler(t *testing.T) {
+	s := &struct {
+		Value binaryOnlyUnmarshaler
+	}{}
+
+	m := map[string]interface{}{
+		"Value": []byte("raw bytes"),
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, cast.Interfaces()))
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(s.Value.Value) != "raw bytes" {
+		t.Errorf("Value = %q, want %q", s.Value.Value, "raw bytes")
+	}
+}
@@ -0,0 +1,63 @@
+// Package flag provides behaviors driven by struct tag flags, the options
+// that follow the name portion of a tag (e.g. `structmap:"name,required"`).
+package flag
+
+import (
+	"fmt"
+
+	"github.com/amulets/structmap"
+	"github.com/amulets/structmap/behavior"
+)
+
+// Required fails the decode when the field has no value and its tag carries
+// the "required" flag
+func Required(tag string) structmap.MutationFunc {
+	return behavior.New(func(field *structmap.FieldPart) error {
+		if !hasFlag(field, tag, "required") {
+			return nil
+		}
+
+		if field.Value == nil {
+			return fmt.Errorf("field %s is required", field.Name)
+		}
+
+		return nil
+	})
+}
+
+// NoEmbedded stops an anonymous (embedded) field from being flattened into
+// its parent when the tag carries the "noembedded" flag: the field is then
+// decoded from / encoded to its own nested map, keyed by its resolved name
+func NoEmbedded(tag string) structmap.MutationFunc {
+	return behavior.New(func(field *structmap.FieldPart) error {
+		if hasFlag(field, tag, "noembedded") {
+			field.NoEmbedded = true
+		}
+
+		return nil
+	})
+}
+
+// OmitEmpty marks a field to be left out of the encoded map when it holds
+// its zero value, when the tag carries the "omitempty" flag
+func OmitEmpty(tag string) structmap.MutationFunc {
+	return behavior.New(func(field *structmap.FieldPart) error {
+		if hasFlag(field, tag, "omitempty") {
+			field.OmitEmpty = true
+		}
+
+		return nil
+	})
+}
+
+func hasFlag(field *structmap.FieldPart, tag, flag string) bool {
+	_, opts := structmap.ParseTag(field.Tag.Get(tag))
+
+	for _, opt := range opts {
+		if opt == flag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+// Package name provides behaviors that resolve the map key a struct field
+// is decoded from (and encoded to).
+package name
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/amulets/structmap"
+	"github.com/amulets/structmap/behavior"
+)
+
+// FromTag resolves the field name from the given struct tag (e.g. `json:"name,omitempty"`),
+// leaving the field's current name untouched when the tag is absent, empty or "-"
+func FromTag(tag string) structmap.MutationFunc {
+	return behavior.New(func(field *structmap.FieldPart) error {
+		value, _ := structmap.ParseTag(field.Tag.Get(tag))
+
+		if value != "" && value != "-" {
+			field.Name = value
+		}
+
+		return nil
+	})
+}
+
+// Noop keeps the field's Go name unchanged
+var Noop = behavior.New(func(field *structmap.FieldPart) error {
+	return nil
+})
+
+// FromSnake converts the field's current name to snake_case
+var FromSnake = behavior.New(func(field *structmap.FieldPart) error {
+	field.Name = toSnakeCase(field.Name)
+
+	return nil
+})
+
+// Discovery tries each of the given name behaviors in order, keeping the
+// result of the first one that actually changes the field's name
+func Discovery(behaviors ...structmap.MutationFunc) structmap.MutationFunc {
+	return behavior.New(func(field *structmap.FieldPart) error {
+		original := field.Name
+
+		for _, b := range behaviors {
+			field.Name = original
+
+			if err := b(field); err != nil {
+				return err
+			}
+
+			if field.Name != original {
+				return nil
+			}
+		}
+
+		field.Name = original
+
+		return nil
+	})
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package structmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError collects every field-level error produced by a single Decode
+// call when the Decoder was built with WithErrorAccumulation
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap exposes the collected errors to errors.Is/errors.As
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// pathError prefixes err with the dotted field path it occurred at
+func pathError(path []string, err error) error {
+	if len(path) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+}
+
+// decodeContext carries the state that must survive recursive Decode calls:
+// the dotted path to the field currently being decoded, the errors
+// collected so far (when error accumulation is enabled), and the Metadata
+// report being built (when metadata collection is enabled).
+type decodeContext struct {
+	path   []string
+	errors *MultiError
+	meta   *Metadata
+}
+
+func (ctx *decodeContext) child(name string) *decodeContext {
+	path := make([]string, len(ctx.path)+1)
+	copy(path, ctx.path)
+	path[len(ctx.path)] = name
+
+	return &decodeContext{path: path, errors: ctx.errors, meta: ctx.meta}
+}
+
+func (ctx *decodeContext) dotted(name string) string {
+	return strings.Join(ctx.child(name).path, ".")
+}
+
+func (ctx *decodeContext) recordKey() {
+	if ctx.meta != nil {
+		ctx.meta.Keys = append(ctx.meta.Keys, strings.Join(ctx.path, "."))
+	}
+}
+
+func (ctx *decodeContext) recordUnset() {
+	if ctx.meta != nil {
+		ctx.meta.Unset = append(ctx.meta.Unset, strings.Join(ctx.path, "."))
+	}
+}
+
+func (ctx *decodeContext) recordUnused(name string) {
+	if ctx.meta != nil {
+		ctx.meta.Unused = append(ctx.meta.Unused, ctx.dotted(name))
+	}
+}
+
+// fail records err at the current path: accumulated if ctx.errors is set,
+// returned immediately otherwise
+func (ctx *decodeContext) fail(err error) error {
+	wrapped := pathError(ctx.path, err)
+
+	if ctx.errors != nil {
+		ctx.errors.Errors = append(ctx.errors.Errors, wrapped)
+
+		return nil
+	}
+
+	return wrapped
+}
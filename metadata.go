@@ -0,0 +1,13 @@
+package structmap
+
+// Metadata reports on a single Decode call: which field paths were
+// successfully populated, which input keys had no matching field, and
+// which target fields were left at their zero value.
+type Metadata struct {
+	// Keys holds the dotted path of every field that received a value
+	Keys []string
+	// Unused holds the dotted path of every input map key with no matching field
+	Unused []string
+	// Unset holds the dotted path of every target field left at its zero value
+	Unset []string
+}
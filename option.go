@@ -0,0 +1,68 @@
+package structmap
+
+import "strings"
+
+// Option configures a Decoder created with New
+type Option func(*Decoder)
+
+// WithBehaviors appends the given behaviors (MutationFuncs) to the Decoder,
+// run in order for every field
+func WithBehaviors(behaviors ...MutationFunc) Option {
+	return func(decoder *Decoder) {
+		decoder.mutations = append(decoder.mutations, behaviors...)
+	}
+}
+
+// New builds a Decoder configured with the given options
+func New(opts ...Option) *Decoder {
+	decoder := NewDecoder()
+
+	for _, opt := range opts {
+		opt(decoder)
+	}
+
+	return decoder
+}
+
+// WithErrorAccumulation makes Decode collect every field-level error instead
+// of returning on the first one, wrapping them in a *MultiError with each
+// error prefixed by its dotted field path
+func WithErrorAccumulation() Option {
+	return func(decoder *Decoder) {
+		decoder.accumulateErrors = true
+	}
+}
+
+// WithMetadata populates meta with a report of the next Decode call: which
+// field paths were set (Keys), which input keys matched no field (Unused),
+// and which target fields were left at their zero value (Unset)
+func WithMetadata(meta *Metadata) Option {
+	return func(decoder *Decoder) {
+		decoder.metadata = meta
+	}
+}
+
+// WithErrorUnused makes Decode fail when the input map has keys that match
+// no target field, rather than silently ignoring them
+func WithErrorUnused() Option {
+	return func(decoder *Decoder) {
+		decoder.errorUnused = true
+	}
+}
+
+// Encode is a convenience wrapper around New(opts...).Encode(from, to)
+func Encode(from interface{}, to *map[string]interface{}, opts ...Option) error {
+	return New(opts...).Encode(from, to)
+}
+
+// ParseTag splits a struct tag value on commas, returning the leading
+// name/value portion and the remaining flags (e.g. "required", "omitempty")
+func ParseTag(tag string) (string, []string) {
+	if tag == "" {
+		return "", nil
+	}
+
+	parts := strings.Split(tag, ",")
+
+	return parts[0], parts[1:]
+}
@@ -0,0 +1,75 @@
+package structmap
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type (
+	// Field is a single struct field, exposed in a reflect-free-ish shape
+	// so Decoder can walk it without repeating reflection boilerplate.
+	Field struct {
+		Name      string
+		Tag       reflect.StructTag
+		Type      reflect.Type
+		Value     reflect.Value
+		Anonymous bool
+	}
+
+	// Struct wraps a struct value (or a pointer to one) and exposes its fields
+	Struct struct {
+		value reflect.Value
+	}
+)
+
+// NewStruct wraps v, which must be a struct or a pointer to one. A pointer
+// is required to later mutate fields, as Decoder.Decode does; a plain
+// struct value is enough for read-only access, as Decoder.Encode does.
+func NewStruct(v interface{}) (*Struct, error) {
+	value := reflect.ValueOf(v)
+
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("structmap: expected a struct or a pointer to a struct, got %T", v)
+	}
+
+	return &Struct{value: value}, nil
+}
+
+// Fields returns the exported fields of the wrapped struct
+func (s *Struct) Fields() []*Field {
+	typ := s.value.Type()
+
+	fields := make([]*Field, 0, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		fields = append(fields, &Field{
+			Name:      sf.Name,
+			Tag:       sf.Tag,
+			Type:      sf.Type,
+			Value:     s.value.Field(i),
+			Anonymous: sf.Anonymous,
+		})
+	}
+
+	return fields
+}
+
+// IsZero reports whether the field currently holds its zero value
+func (f *Field) IsZero() bool {
+	return f.Value.IsZero()
+}
+
+// IsEmbedded reports whether the field is an anonymous (embedded) field
+func (f *Field) IsEmbedded() bool {
+	return f.Anonymous
+}
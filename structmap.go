@@ -3,16 +3,35 @@ package structmap
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/amulets/structmap/internal"
 )
 
 type (
+	// Direction tells a MutationFunc whether it is running as part of a
+	// Decode (map -> struct) or an Encode (struct -> map), so the same
+	// MutationFunc can behave symmetrically in both flows.
+	Direction int
+
 	// FieldPart is a Field representation
 	FieldPart struct {
-		Name  string
-		Value interface{}
-		Type  reflect.Type
-		Tag   reflect.StructTag
-		Skip  bool
+		Name       string
+		Value      interface{}
+		Type       reflect.Type
+		Tag        reflect.StructTag
+		Skip       bool
+		NoEmbedded bool
+		OmitEmpty  bool
+		Direction  Direction
+		// Target is the field's own reflect.Value, only populated while
+		// decoding. It lets a MutationFunc (such as cast.Interfaces) address
+		// the destination directly, e.g. to call an Unmarshaler on it.
+		Target reflect.Value
+		// Path is the dotted location of this field from the root of the
+		// Decode call, e.g. []string{"SubStruct", "Number"}, only populated
+		// while decoding. Useful for MutationFuncs that want to produce
+		// path-aware errors.
+		Path []string
 	}
 
 	// MutationFunc that's change field information
@@ -20,10 +39,26 @@ type (
 
 	// Decoder is a structmap
 	Decoder struct {
-		mutations []MutationFunc
+		mutations        []MutationFunc
+		accumulateErrors bool
+		metadata         *Metadata
+		errorUnused      bool
+	}
+
+	// Unmarshaler is implemented by types that know how to build themselves
+	// from an arbitrary decoded value, bypassing the default conversion rules
+	Unmarshaler interface {
+		UnmarshalStructmap(value interface{}) error
 	}
 )
 
+const (
+	// DirectionDecode marks a MutationFunc run while decoding a map into a struct
+	DirectionDecode Direction = iota
+	// DirectionEncode marks a MutationFunc run while encoding a struct into a map
+	DirectionEncode
+)
+
 // NewDecoder instance of Decoder
 func NewDecoder() *Decoder {
 	return &Decoder{}
@@ -44,40 +79,111 @@ func (decoder *Decoder) Decode(from map[string]interface{}, to interface{}) (err
 		}
 	}()
 
+	// WithErrorUnused relies on Unused tracking even when the caller never
+	// asked for a Metadata report via WithMetadata
+	meta := decoder.metadata
+	if meta == nil && decoder.errorUnused {
+		meta = &Metadata{}
+	}
+
+	ctx := &decodeContext{meta: meta}
+
+	if decoder.accumulateErrors {
+		ctx.errors = &MultiError{}
+	}
+
+	if ctx.meta != nil {
+		*ctx.meta = Metadata{}
+	}
+
+	if err := decoder.decode(from, to, ctx, map[string]bool{}, true); err != nil {
+		return err
+	}
+
+	if ctx.errors != nil && len(ctx.errors.Errors) > 0 {
+		return ctx.errors
+	}
+
+	if decoder.errorUnused && ctx.meta != nil && len(ctx.meta.Unused) > 0 {
+		return fmt.Errorf("structmap: unused keys: %v", ctx.meta.Unused)
+	}
+
+	return nil
+}
+
+// decode maps from into to. consumed tracks which keys of from have been
+// matched to a field so far; it is shared across embedded-struct recursion
+// (which reuses the very same from map) and fresh for every other nested
+// struct, so an "owns" call can report the keys of its own from map that
+// went unused.
+func (decoder *Decoder) decode(from map[string]interface{}, to interface{}, ctx *decodeContext, consumed map[string]bool, owns bool) error {
 	s, err := NewStruct(to)
 	if err != nil {
 		return err
 	}
 
 	for _, field := range s.Fields() {
+		fieldCtx := ctx.child(field.Name)
+
 		fp := &FieldPart{
-			Tag:  field.Tag,
-			Type: field.Type,
+			Name:      field.Name,
+			Tag:       field.Tag,
+			Type:      field.Type,
+			Direction: DirectionDecode,
+			Target:    field.Value,
+			Path:      fieldCtx.path,
 		}
 
 		// run mutations
+		var mutationErr error
 		for i, mutation := range decoder.mutations {
 			if err := mutation(fp); err != nil {
-				return err
+				mutationErr = err
+				break
 			}
 
-			// expects there first mutation get field name to get field value
+			// expects there first mutation to resolve the field name before the value is looked up
 			if i == 0 {
-				if fp.Name == "" {
-					fp.Name = field.Name
-				}
-
 				if value, ok := from[fp.Name]; ok {
 					fp.Value = value
+					consumed[fp.Name] = true
 				}
 			}
 		}
 
+		if mutationErr != nil {
+			if err := fieldCtx.fail(mutationErr); err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		if fp.Skip {
+			// A mutation (e.g. cast.Interfaces, via an Unmarshaler) already
+			// set the field directly and asked to bypass the usual
+			// decode/convert path below, but the field was still genuinely
+			// populated and must still show up in Metadata.Keys
+			fieldCtx.recordKey()
+
 			continue
 		}
 
 		if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
+			// A mutation (e.g. a registered cast.Type TypeCaster) may have
+			// already turned fp.Value into a concrete instance of the
+			// field's struct type itself (e.g. a string decoded straight
+			// into a big.Int), rather than a nested map. Assign it like
+			// any other scalar field instead of recursing into decode.
+			if fp.Value != nil {
+				if resultValue := reflect.ValueOf(fp.Value); resultValue.Type() == internal.Type(field.Type) {
+					indirect(field.Value).Set(resultValue)
+					fieldCtx.recordKey()
+
+					continue
+				}
+			}
+
 			value := field.Value
 
 			if field.Value.Kind() == reflect.Ptr && field.IsZero() {
@@ -88,38 +194,71 @@ func (decoder *Decoder) Decode(from map[string]interface{}, to interface{}) (err
 			}
 
 			structFrom := from
+			structConsumed := consumed
+			structOwns := false
+			// A flattened embedded struct's fields live in the very same
+			// from map as their parent's (there's no nested "Inner" key in
+			// the input), so their Metadata paths must stay relative to
+			// ctx, not fieldCtx, to match how recordUnused already reports
+			// leftover keys in that same shared scope unprefixed.
+			structCtx := ctx
 
-			if !field.IsEmbedded() {
+			if !field.IsEmbedded() || fp.NoEmbedded {
 				var ok bool
 				if structFrom, ok = fp.Value.(map[string]interface{}); !ok {
-					return fmt.Errorf("field %s cannot is a embedded struct, will expect that's value is a map[string]interface{}", fp.Name)
+					if err := fieldCtx.fail(fmt.Errorf("field %s cannot is a embedded struct, will expect that's value is a map[string]interface{}", fp.Name)); err != nil {
+						return err
+					}
+
+					continue
 				}
+
+				structConsumed = map[string]bool{}
+				structOwns = true
+				structCtx = fieldCtx
 			}
 
-			if err := decoder.Decode(structFrom, value.Interface()); err != nil {
+			if err := decoder.decode(structFrom, value.Interface(), structCtx, structConsumed, structOwns); err != nil {
 				return err
 			}
 		} else {
-			value := reflect.ValueOf(fp.Value)
-			fieldValue := field.Value
-
-			// Get value element
-			if value.Kind() == reflect.Ptr {
-				value = value.Elem()
-			}
+			// Get value element, following through any depth of pointer/
+			// interface indirection (e.g. a source value of type **string)
+			value := internal.Value(reflect.ValueOf(fp.Value), true)
 
 			// Ignore if no have a value
-			if value.Kind() == reflect.Invalid {
+			if !value.IsValid() {
+				fieldCtx.recordUnset()
+
 				continue
 			}
 
-			// Get field value element
-			if fieldValue.Kind() == reflect.Ptr {
-				if fieldValue.IsZero() {
-					fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+			// Get field value element, allocating through any depth of
+			// pointer indirection (e.g. a field of type **string) so the
+			// leaf is always a settable, non-pointer reflect.Value
+			fieldValue := indirect(field.Value)
+
+			// A slice/array of structs needs its elements decoded one by
+			// one, rather than relying on reflect convertibility, so that
+			// e.g. a []map[string]interface{} can target a []Struct field.
+			// Only take this path when the source elements are actually
+			// maps: a struct-typed element whose source has already been
+			// converted (e.g. by a registered cast.Type caster) must fall
+			// through to the normal assignment below instead.
+			if (fieldValue.Kind() == reflect.Slice || fieldValue.Kind() == reflect.Array) &&
+				internal.Type(fieldValue.Type().Elem()).Kind() == reflect.Struct &&
+				isSequenceOfMaps(value) {
+				// decodeSequence already reports element-level errors
+				// through ctx.fail (with the full dotted path, same as
+				// the nested-struct branch above), so its error, if any,
+				// is propagated as-is rather than wrapped a second time.
+				if err := decoder.decodeSequence(fieldCtx, value, fieldValue); err != nil {
+					return err
 				}
 
-				fieldValue = fieldValue.Elem()
+				fieldCtx.recordKey()
+
+				continue
 			}
 
 			if value.Type().ConvertibleTo(fieldValue.Type()) {
@@ -127,15 +266,283 @@ func (decoder *Decoder) Decode(from map[string]interface{}, to interface{}) (err
 			}
 
 			if value.Kind() != fieldValue.Kind() {
-				return fmt.Errorf("field %s value of type %s is not assignable to type %s", field.Name, value.Type(), fieldValue.Type())
+				if err := fieldCtx.fail(fmt.Errorf("value of type %s is not assignable to type %s", value.Type(), fieldValue.Type())); err != nil {
+					return err
+				}
+
+				continue
 			}
 
-			if field.Value.Kind() == reflect.Ptr {
-				field.Value.Elem().Set(value)
-			} else {
-				field.Value.Set(value)
+			fieldValue.Set(value)
+
+			fieldCtx.recordKey()
+		}
+	}
+
+	if owns {
+		for key := range from {
+			if !consumed[key] {
+				ctx.recordUnused(key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Encode struct to map, walking the same Fields machinery Decode uses so
+// the two stay symmetric: a MutationFunc written for one participates in
+// the other simply by checking FieldPart.Direction.
+func (decoder *Decoder) Encode(from interface{}, to *map[string]interface{}) (err error) {
+	defer func() {
+		if err == nil {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("%v", recovered)
+			}
+		}
+	}()
+
+	s, err := NewStruct(from)
+	if err != nil {
+		return err
+	}
+
+	if *to == nil {
+		*to = map[string]interface{}{}
+	}
+
+	for _, field := range s.Fields() {
+		fp := &FieldPart{
+			Name:      field.Name,
+			Tag:       field.Tag,
+			Type:      field.Type,
+			Direction: DirectionEncode,
+		}
+
+		if field.Value.Kind() == reflect.Ptr {
+			if !field.Value.IsZero() {
+				fp.Value = field.Value.Elem().Interface()
+			}
+		} else {
+			fp.Value = field.Value.Interface()
+		}
+
+		for _, mutation := range decoder.mutations {
+			if err := mutation(fp); err != nil {
+				return err
+			}
+		}
+
+		if fp.Skip {
+			continue
+		}
+
+		if field.IsEmbedded() && !fp.NoEmbedded {
+			value := field.Value
+
+			if value.Kind() == reflect.Ptr {
+				if value.IsZero() {
+					continue
+				}
+
+				value = value.Elem()
+			}
+
+			if err := decoder.Encode(value.Interface(), to); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
+			value := field.Value
+
+			if value.Kind() == reflect.Ptr {
+				if value.IsZero() {
+					if !fp.OmitEmpty {
+						(*to)[fp.Name] = nil
+					}
+
+					continue
+				}
+
+				value = value.Elem()
 			}
+
+			if fp.OmitEmpty && field.IsZero() {
+				continue
+			}
+
+			nested := map[string]interface{}{}
+			if err := decoder.Encode(value.Interface(), &nested); err != nil {
+				return err
+			}
+
+			(*to)[fp.Name] = nested
+
+			continue
 		}
+
+		if fp.OmitEmpty && field.IsZero() {
+			continue
+		}
+
+		(*to)[fp.Name] = fp.Value
+	}
+
+	return nil
+}
+
+// indirect follows value through any depth of pointer indirection,
+// allocating through nil pointers as it goes, and returns the settable
+// non-pointer leaf
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr {
+		if value.IsZero() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+
+		value = value.Elem()
+	}
+
+	return value
+}
+
+// isSequenceOfMaps reports whether value is a slice/array whose every
+// element is a map[string]interface{} (through pointer/interface indirection)
+func isSequenceOfMaps(value reflect.Value) bool {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return false
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		elem := internal.Value(value.Index(i), true)
+
+		if !elem.IsValid() {
+			return false
+		}
+
+		if _, ok := elem.Interface().(map[string]interface{}); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeSequence decodes a slice/array of map[string]interface{} (value)
+// into a slice/array of structs (target), reusing decoder.decode per element
+func (decoder *Decoder) decodeSequence(ctx *decodeContext, value reflect.Value, target reflect.Value) error {
+	elemType := target.Type().Elem()
+	structType := elemType
+	isPtr := elemType.Kind() == reflect.Ptr
+
+	if isPtr {
+		structType = elemType.Elem()
+	}
+
+	length := value.Len()
+
+	var result reflect.Value
+	if target.Kind() == reflect.Array {
+		result = reflect.New(target.Type()).Elem()
+
+		if length > result.Len() {
+			length = result.Len()
+		}
+	} else {
+		result = reflect.MakeSlice(target.Type(), length, length)
+	}
+
+	for i := 0; i < length; i++ {
+		item := internal.Value(value.Index(i), true)
+		elemCtx := ctx.child(fmt.Sprintf("[%d]", i))
+
+		itemFrom, ok := item.Interface().(map[string]interface{})
+		if !ok {
+			// Wrapped here, at the point the error is created, rather than
+			// by the caller: the per-element decode below already reports
+			// its own errors through elemCtx.fail with the full dotted
+			// path, so every error decodeSequence returns is either nil or
+			// already path-prefixed, never needing a second wrap upstream.
+			if err := elemCtx.fail(fmt.Errorf("not a map[string]interface{}")); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		elemPtr := reflect.New(structType)
+
+		if err := decoder.decode(itemFrom, elemPtr.Interface(), elemCtx, map[string]bool{}, true); err != nil {
+			return err
+		}
+
+		if isPtr {
+			result.Index(i).Set(elemPtr)
+		} else {
+			result.Index(i).Set(elemPtr.Elem())
+		}
+	}
+
+	target.Set(result)
+
+	return nil
+}
+
+// DecodeSlice decodes from, a slice of map[string]interface{}, into to,
+// which must be a pointer to a []T or []*T where T is a struct. It reuses
+// Decode for every element, so the same behaviors apply per item.
+func (decoder *Decoder) DecodeSlice(from []interface{}, to interface{}) (err error) {
+	defer func() {
+		if err == nil {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("%v", recovered)
+			}
+		}
+	}()
+
+	target := reflect.ValueOf(to)
+
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("structmap: to must be a pointer to a slice, got %T", to)
+	}
+
+	slice := target.Elem()
+
+	if internal.Type(slice.Type().Elem()).Kind() != reflect.Struct {
+		return fmt.Errorf("structmap: to must be a pointer to a slice of structs, got %T", to)
+	}
+
+	value := reflect.ValueOf(from)
+	// WithErrorUnused relies on Unused tracking even when the caller never
+	// asked for a Metadata report via WithMetadata
+	meta := decoder.metadata
+	if meta == nil && decoder.errorUnused {
+		meta = &Metadata{}
+	}
+
+	ctx := &decodeContext{meta: meta}
+
+	if decoder.accumulateErrors {
+		ctx.errors = &MultiError{}
+	}
+
+	if ctx.meta != nil {
+		*ctx.meta = Metadata{}
+	}
+
+	if err := decoder.decodeSequence(ctx, value, slice); err != nil {
+		return err
+	}
+
+	if ctx.errors != nil && len(ctx.errors.Errors) > 0 {
+		return ctx.errors
+	}
+
+	if decoder.errorUnused && ctx.meta != nil && len(ctx.meta.Unused) > 0 {
+		return fmt.Errorf("structmap: unused keys: %v", ctx.meta.Unused)
 	}
 
 	return nil
@@ -1,9 +1,11 @@
 package structmap_test
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -859,3 +861,413 @@ func TestIntToBigInt(t *testing.T) {
 
 	t.Logf("%+v", s)
 }
+
+func TestEncode(t *testing.T) {
+	age := 20
+
+	s := &Data{
+		Name: "a",
+		Age:  20,
+	}
+
+	m := map[string]interface{}{}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.Encode(s, &m); err != nil {
+		t.Error(err)
+	}
+
+	if m["Name"] != "a" {
+		t.Errorf("Name should be 'a': %#v", m["Name"])
+	}
+
+	if m["Age"] != age {
+		t.Errorf("Age should be %d: %#v", age, m["Age"])
+	}
+}
+
+func TestEncodePointer(t *testing.T) {
+	nameValue := "a"
+
+	s := &struct {
+		Name *string
+		Age  *int
+	}{
+		Name: &nameValue,
+	}
+
+	m := map[string]interface{}{}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.Encode(s, &m); err != nil {
+		t.Error(err)
+	}
+
+	if m["Name"] != "a" {
+		t.Errorf("Name should be 'a': %#v", m["Name"])
+	}
+
+	if m["Age"] != nil {
+		t.Errorf("Age should be nil: %#v", m["Age"])
+	}
+}
+
+func TestEncodeMutation(t *testing.T) {
+	s := &Data{
+		Name: "a",
+		Age:  20,
+	}
+
+	m := map[string]interface{}{}
+
+	upper := behavior.New(func(field *structmap.FieldPart) error {
+		if field.Direction != structmap.DirectionEncode {
+			return nil
+		}
+
+		if v, ok := field.Value.(string); ok {
+			field.Value = strings.ToUpper(v)
+		}
+
+		return nil
+	})
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, upper))
+
+	if err := sm.Encode(s, &m); err != nil {
+		t.Error(err)
+	}
+
+	if m["Name"] != "A" {
+		t.Errorf("Name should be mutated to 'A': %#v", m["Name"])
+	}
+}
+
+func TestEncodeOmitEmpty(t *testing.T) {
+	s := &struct {
+		Name string `structmap:",omitempty"`
+		Age  int    `structmap:",omitempty"`
+	}{
+		Age: 20,
+	}
+
+	m := map[string]interface{}{}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, flag.OmitEmpty("structmap")))
+
+	if err := sm.Encode(s, &m); err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := m["Name"]; ok {
+		t.Errorf("Name should have been omitted: %#v", m["Name"])
+	}
+
+	if m["Age"] != 20 {
+		t.Errorf("Age should be 20: %#v", m["Age"])
+	}
+}
+
+func TestEncodeEmbedded(t *testing.T) {
+	s := &SubStruct{
+		SubSubStruct: &SubSubStruct{
+			Address: "Street A",
+		},
+	}
+
+	m := map[string]interface{}{}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.Encode(s, &m); err != nil {
+		t.Error(err)
+	}
+
+	if m["Address"] != "Street A" {
+		t.Errorf("Address should be flattened from the embedded struct: %#v", m["Address"])
+	}
+}
+
+func TestErrorAccumulation(t *testing.T) {
+	s := &struct {
+		Name string `structmap:",required"`
+		Age  int    `structmap:",required"`
+	}{}
+
+	m := map[string]interface{}{}
+
+	sm := structmap.New(
+		structmap.WithErrorAccumulation(),
+		structmap.WithBehaviors(name.Noop, flag.Required("structmap")),
+	)
+
+	err := sm.Decode(m, s)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	var multiErr *structmap.MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("expected a *structmap.MultiError, got %T", err)
+	}
+
+	if len(multiErr.Errors) != 2 {
+		t.Fatalf("expected 2 accumulated errors, got %d: %v", len(multiErr.Errors), multiErr.Errors)
+	}
+
+	if !strings.Contains(multiErr.Errors[0].Error(), "Name") {
+		t.Errorf("first error should mention the Name field: %v", multiErr.Errors[0])
+	}
+
+	if !strings.Contains(multiErr.Errors[1].Error(), "Age") {
+		t.Errorf("second error should mention the Age field: %v", multiErr.Errors[1])
+	}
+}
+
+func TestErrorAccumulationOff(t *testing.T) {
+	s := &struct {
+		Name string `structmap:",required"`
+		Age  int    `structmap:",required"`
+	}{}
+
+	m := map[string]interface{}{}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop, flag.Required("structmap")))
+
+	err := sm.Decode(m, s)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	var multiErr *structmap.MultiError
+	if errors.As(err, &multiErr) {
+		t.Fatalf("expected a plain error without WithErrorAccumulation, got a *structmap.MultiError: %v", multiErr)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	s := &struct {
+		Name string
+		Age  int
+	}{}
+
+	m := map[string]interface{}{
+		"Name":  "a",
+		"Extra": "unused",
+	}
+
+	var meta structmap.Metadata
+
+	sm := structmap.New(
+		structmap.WithBehaviors(name.Noop),
+		structmap.WithMetadata(&meta),
+	)
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(meta.Keys, []string{"Name"}) {
+		t.Errorf("Keys should list the populated fields: %#v", meta.Keys)
+	}
+
+	if !reflect.DeepEqual(meta.Unset, []string{"Age"}) {
+		t.Errorf("Unset should list the fields left at zero value: %#v", meta.Unset)
+	}
+
+	if !reflect.DeepEqual(meta.Unused, []string{"Extra"}) {
+		t.Errorf("Unused should list the input keys with no matching field: %#v", meta.Unused)
+	}
+}
+
+func TestErrorUnused(t *testing.T) {
+	s := &struct {
+		Name string
+	}{}
+
+	m := map[string]interface{}{
+		"Name":  "a",
+		"Extra": "unused",
+	}
+
+	sm := structmap.New(
+		structmap.WithBehaviors(name.Noop),
+		structmap.WithErrorUnused(),
+	)
+
+	if err := sm.Decode(m, s); err == nil {
+		t.Error("expected an error for the unused 'Extra' key, got nil")
+	}
+}
+
+func TestErrorUnusedOff(t *testing.T) {
+	s := &struct {
+		Name string
+	}{}
+
+	m := map[string]interface{}{
+		"Name":  "a",
+		"Extra": "unused",
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Errorf("unused keys should be ignored by default: %v", err)
+	}
+}
+
+func TestDecodeSlice(t *testing.T) {
+	type Item struct {
+		Name string
+		Age  int
+	}
+
+	from := []interface{}{
+		map[string]interface{}{"Name": "a", "Age": 1},
+		map[string]interface{}{"Name": "b", "Age": 2},
+	}
+
+	var items []Item
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.DecodeSlice(from, &items); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Item{
+		{Name: "a", Age: 1},
+		{Name: "b", Age: 2},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Expected = %+v; got = %+v", expected, items)
+	}
+}
+
+func TestDecodeSlicePointers(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+
+	from := []interface{}{
+		map[string]interface{}{"Name": "a"},
+	}
+
+	var items []*Item
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.DecodeSlice(from, &items); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items[0].Name != "a" {
+		t.Errorf("unexpected result: %+v", items)
+	}
+}
+
+func TestDecodeSliceNotAStructSlice(t *testing.T) {
+	var ints []int
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	if err := sm.DecodeSlice([]interface{}{1, 2}, &ints); err == nil {
+		t.Error("expected an error when the target isn't a slice of structs")
+	}
+}
+
+func TestDecodeSequenceErrorNotDoubleWrapped(t *testing.T) {
+	type Item struct {
+		Number int
+	}
+
+	s := &struct {
+		Items []Item
+	}{}
+
+	m := map[string]interface{}{
+		"Items": []interface{}{
+			map[string]interface{}{"Number": "nope"},
+		},
+	}
+
+	sm := structmap.New(structmap.WithBehaviors(name.Noop))
+
+	err := sm.Decode(m, s)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	const want = "Items.[0].Number: value of type string is not assignable to type int"
+
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestMetadataRecordsSkippedUnmarshaledFields(t *testing.T) {
+	s := &struct {
+		Number big.Int
+		Other  string
+	}{}
+
+	m := map[string]interface{}{
+		"Number": "123",
+		"Other":  "hi",
+	}
+
+	var meta structmap.Metadata
+
+	sm := structmap.New(
+		structmap.WithBehaviors(name.Noop, cast.Interfaces()),
+		structmap.WithMetadata(&meta),
+	)
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(meta.Keys, []string{"Number", "Other"}) {
+		t.Errorf("Keys should list every populated field, including those an Unmarshaler handled: %#v", meta.Keys)
+	}
+}
+
+func TestMetadataEmbedded(t *testing.T) {
+	type Inner struct {
+		A string
+	}
+
+	s := &struct {
+		Inner
+		B string
+	}{}
+
+	m := map[string]interface{}{
+		"A":     "a",
+		"B":     "b",
+		"Extra": "unused",
+	}
+
+	var meta structmap.Metadata
+
+	sm := structmap.New(
+		structmap.WithBehaviors(name.Noop),
+		structmap.WithMetadata(&meta),
+	)
+
+	if err := sm.Decode(m, s); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(meta.Keys, []string{"A", "B"}) {
+		t.Errorf("Keys for a flattened embedded struct should be relative to the shared input map, not prefixed by the Go field name: %#v", meta.Keys)
+	}
+
+	if !reflect.DeepEqual(meta.Unused, []string{"Extra"}) {
+		t.Errorf("Unused should list the input keys with no matching field: %#v", meta.Unused)
+	}
+}